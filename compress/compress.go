@@ -0,0 +1,95 @@
+// Package compress provides the pluggable compression algorithms backups
+// can be written with, selected per service via configuration and recorded
+// in the object name so Restore can reverse it transparently.
+package compress
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+)
+
+// Compressor wraps a compression algorithm as a streaming io.Writer/Reader
+// pair, so postgres.Backup and postgres.Restore never need to know which
+// algorithm is in use.
+type Compressor interface {
+	// Name identifies the algorithm in config and in manifests, e.g. "gzip".
+	Name() string
+	// Extension is appended to backup object names, e.g. ".gz".
+	Extension() string
+	// NewWriter wraps w so writes to it are compressed.
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+	// NewReader wraps r so reads from it are decompressed.
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+// ByName returns the Compressor registered under name, e.g. "gzip", "zstd"
+// or "lz4".
+func ByName(name string) (Compressor, error) {
+	switch name {
+	case "", "gzip":
+		return gzipCompressor{}, nil
+	case "zstd":
+		return zstdCompressor{}, nil
+	case "lz4":
+		return lz4Compressor{}, nil
+	default:
+		return nil, fmt.Errorf("compress: unknown algorithm %q", name)
+	}
+}
+
+// ByExtension returns the Compressor whose Extension is a suffix of
+// objectPath, so Restore can detect the algorithm a backup was written
+// with without consulting its manifest.
+func ByExtension(objectPath string) (Compressor, error) {
+	for _, c := range []Compressor{gzipCompressor{}, zstdCompressor{}, lz4Compressor{}} {
+		if hasSuffix(objectPath, c.Extension()) {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("compress: could not detect compression algorithm from object name %q", objectPath)
+}
+
+func hasSuffix(s, suffix string) bool {
+	return len(s) >= len(suffix) && s[len(s)-len(suffix):] == suffix
+}
+
+type gzipCompressor struct{}
+
+func (gzipCompressor) Name() string      { return "gzip" }
+func (gzipCompressor) Extension() string { return ".gz" }
+func (gzipCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+func (gzipCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+type zstdCompressor struct{}
+
+func (zstdCompressor) Name() string      { return "zstd" }
+func (zstdCompressor) Extension() string { return ".zst" }
+func (zstdCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return zstd.NewWriter(w)
+}
+func (zstdCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return zr.IOReadCloser(), nil
+}
+
+type lz4Compressor struct{}
+
+func (lz4Compressor) Name() string      { return "lz4" }
+func (lz4Compressor) Extension() string { return ".lz4" }
+func (lz4Compressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return lz4.NewWriter(w), nil
+}
+func (lz4Compressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(lz4.NewReader(r)), nil
+}
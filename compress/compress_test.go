@@ -0,0 +1,76 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	plaintext := []byte("the quick brown fox jumps over the lazy dog, the quick brown fox jumps over the lazy dog")
+
+	for _, name := range []string{"", "gzip", "zstd", "lz4"} {
+		t.Run(name, func(t *testing.T) {
+			compressor, err := ByName(name)
+			if err != nil {
+				t.Fatalf("ByName(%q): %v", name, err)
+			}
+
+			var buf bytes.Buffer
+			w, err := compressor.NewWriter(&buf)
+			if err != nil {
+				t.Fatalf("NewWriter: %v", err)
+			}
+			if _, err := w.Write(plaintext); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			r, err := compressor.NewReader(&buf)
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer r.Close()
+
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("ReadAll: %v", err)
+			}
+			if !bytes.Equal(got, plaintext) {
+				t.Fatalf("round trip mismatch: got %q, want %q", got, plaintext)
+			}
+		})
+	}
+}
+
+func TestByNameUnknownAlgorithm(t *testing.T) {
+	if _, err := ByName("bogus"); err == nil {
+		t.Fatal("ByName(\"bogus\"): want error, got nil")
+	}
+}
+
+func TestByExtension(t *testing.T) {
+	cases := []struct {
+		objectPath string
+		want       string
+	}{
+		{"dump.sql.gz", "gzip"},
+		{"dump.sql.zst", "zstd"},
+		{"dump.sql.lz4", "lz4"},
+	}
+	for _, tc := range cases {
+		c, err := ByExtension(tc.objectPath)
+		if err != nil {
+			t.Fatalf("ByExtension(%q): %v", tc.objectPath, err)
+		}
+		if c.Name() != tc.want {
+			t.Fatalf("ByExtension(%q) = %q, want %q", tc.objectPath, c.Name(), tc.want)
+		}
+	}
+
+	if _, err := ByExtension("dump.sql"); err == nil {
+		t.Fatal("ByExtension(\"dump.sql\"): want error, got nil")
+	}
+}
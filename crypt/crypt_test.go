@@ -0,0 +1,98 @@
+package crypt
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	key := bytes.Repeat([]byte{0x24}, KeySize)
+	plaintext := bytes.Repeat([]byte("the quick brown fox jumps over the lazy dog. "), 1<<16/47+1)
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, key)
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	r, err := NewReader(&buf, key)
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer r.Close()
+
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Fatalf("round trip mismatch: got %d bytes, want %d bytes", len(got), len(plaintext))
+	}
+}
+
+func TestNewWriterRejectsWrongKeySize(t *testing.T) {
+	if _, err := NewWriter(&bytes.Buffer{}, []byte("too short")); err == nil {
+		t.Fatal("NewWriter with a short key: want error, got nil")
+	}
+}
+
+func TestReaderRejectsWrongKey(t *testing.T) {
+	key := bytes.Repeat([]byte{0x24}, KeySize)
+	wrongKey := bytes.Repeat([]byte{0x99}, KeySize)
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, key)
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	if _, err := w.Write([]byte("secret data")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	r, err := NewReader(&buf, wrongKey)
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer r.Close()
+
+	if _, err := io.ReadAll(r); err == nil {
+		t.Fatal("reading with the wrong key: want error, got nil")
+	}
+}
+
+func TestReaderRejectsTruncatedFrame(t *testing.T) {
+	key := bytes.Repeat([]byte{0x24}, KeySize)
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, key)
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	if _, err := w.Write([]byte("secret data")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	truncated := buf.Bytes()[:buf.Len()-1]
+	r, err := NewReader(bytes.NewReader(truncated), key)
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer r.Close()
+
+	if _, err := io.ReadAll(r); err == nil {
+		t.Fatal("reading a truncated frame: want error, got nil")
+	}
+}
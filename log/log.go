@@ -0,0 +1,41 @@
+// Package log provides the process-wide structured logger of
+// appcloud-backman-app.
+package log
+
+import (
+	"os"
+
+	"github.com/sirupsen/logrus"
+)
+
+var logger = logrus.New()
+
+func init() {
+	logger.SetOutput(os.Stderr)
+	logger.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
+}
+
+// SetLevel configures the minimum level that gets logged.
+func SetLevel(level logrus.Level) {
+	logger.SetLevel(level)
+}
+
+func Debugf(format string, args ...interface{}) {
+	logger.Debugf(format, args...)
+}
+
+func Infof(format string, args ...interface{}) {
+	logger.Infof(format, args...)
+}
+
+func Errorf(format string, args ...interface{}) {
+	logger.Errorf(format, args...)
+}
+
+func Errorln(args ...interface{}) {
+	logger.Errorln(args...)
+}
+
+func Warnf(format string, args ...interface{}) {
+	logger.Warnf(format, args...)
+}
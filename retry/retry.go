@@ -0,0 +1,149 @@
+// Package retry implements exponential backoff with jitter for operations
+// that fail transiently, such as S3 uploads and pg_dump invocations flaking
+// on a network blip.
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+)
+
+// Config controls the backoff schedule of Do.
+type Config struct {
+	// MaxAttempts is the maximum number of times the operation is run,
+	// including the first attempt. Defaults to 3 if unset.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry. Defaults to 1s.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between retries. Defaults to 30s.
+	MaxBackoff time.Duration
+	// MaxElapsedTime bounds the total time spent retrying, across all
+	// attempts. Zero means unbounded.
+	MaxElapsedTime time.Duration
+	// IsRetryable classifies whether err should be retried. Defaults to
+	// IsRetryable from this package.
+	IsRetryable func(error) bool
+}
+
+func (c Config) maxAttempts() int {
+	if c.MaxAttempts > 0 {
+		return c.MaxAttempts
+	}
+	return 3
+}
+
+func (c Config) initialBackoff() time.Duration {
+	if c.InitialBackoff > 0 {
+		return c.InitialBackoff
+	}
+	return time.Second
+}
+
+func (c Config) maxBackoff() time.Duration {
+	if c.MaxBackoff > 0 {
+		return c.MaxBackoff
+	}
+	return 30 * time.Second
+}
+
+func (c Config) isRetryable(err error) bool {
+	if c.IsRetryable != nil {
+		return c.IsRetryable(err)
+	}
+	return IsRetryable(err)
+}
+
+// Do runs fn, retrying with exponential backoff and jitter while
+// cfg.isRetryable(err) and attempts/elapsed time remain within cfg's limits.
+// Before each retry, reset (if non-nil) is called so the caller can rebuild
+// whatever connection or pipeline fn depends on. Do returns the last error
+// if all attempts are exhausted, or ctx.Err() if ctx is cancelled while
+// waiting between attempts.
+func Do(ctx context.Context, cfg Config, reset func(), fn func() error) error {
+	start := time.Now()
+	backoff := cfg.initialBackoff()
+
+	var lastErr error
+	for attempt := 1; attempt <= cfg.maxAttempts(); attempt++ {
+		if attempt > 1 && reset != nil {
+			reset()
+		}
+
+		lastErr = fn()
+		if lastErr == nil {
+			return nil
+		}
+		if !cfg.isRetryable(lastErr) {
+			return lastErr
+		}
+		if attempt == cfg.maxAttempts() {
+			break
+		}
+		if cfg.MaxElapsedTime > 0 && time.Since(start)+backoff > cfg.MaxElapsedTime {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(jitter(backoff)):
+		}
+
+		backoff *= 2
+		if backoff > cfg.maxBackoff() {
+			backoff = cfg.maxBackoff()
+		}
+	}
+	return lastErr
+}
+
+// jitter returns a duration in [d/2, d), so concurrent retries don't
+// thunder back against the same endpoint in lockstep.
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)))
+}
+
+// IsRetryable classifies network errors, broken pipes and S3's own
+// throttling/5xx responses as worth retrying.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.ErrClosedPipe) {
+		return true
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, substr := range []string{
+		"broken pipe",
+		"connection reset",
+		"slow down",
+		"slowdown",
+		"internal error",
+		"service unavailable",
+		"request timeout",
+		"timeout",
+	} {
+		if strings.Contains(msg, substr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDoRetriesUntilSuccess(t *testing.T) {
+	var attempts int
+	err := Do(context.Background(), Config{
+		MaxAttempts:    5,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+	}, nil, func() error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("connection reset by peer")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if attempts != 3 {
+		t.Fatalf("attempts = %d, want 3", attempts)
+	}
+}
+
+func TestDoStopsAfterMaxAttempts(t *testing.T) {
+	var attempts int
+	retryable := errors.New("slow down")
+	err := Do(context.Background(), Config{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+	}, nil, func() error {
+		attempts++
+		return retryable
+	})
+	if !errors.Is(err, retryable) {
+		t.Fatalf("err = %v, want %v", err, retryable)
+	}
+	if attempts != 3 {
+		t.Fatalf("attempts = %d, want 3", attempts)
+	}
+}
+
+func TestDoDoesNotRetryNonRetryableErrors(t *testing.T) {
+	var attempts int
+	permanent := errors.New("no such file or directory")
+	err := Do(context.Background(), Config{MaxAttempts: 5}, nil, func() error {
+		attempts++
+		return permanent
+	})
+	if !errors.Is(err, permanent) {
+		t.Fatalf("err = %v, want %v", err, permanent)
+	}
+	if attempts != 1 {
+		t.Fatalf("attempts = %d, want 1 (should not retry)", attempts)
+	}
+}
+
+func TestDoCallsReset(t *testing.T) {
+	var resets int
+	attempts := 0
+	err := Do(context.Background(), Config{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+	}, func() { resets++ }, func() error {
+		attempts++
+		if attempts < 2 {
+			return errors.New("timeout")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if resets != 1 {
+		t.Fatalf("resets = %d, want 1 (not called before the first attempt)", resets)
+	}
+}
+
+func TestDoRespectsContextCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := Do(ctx, Config{
+		MaxAttempts:    5,
+		InitialBackoff: time.Hour,
+	}, nil, func() error {
+		return errors.New("timeout")
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+}
+
+func TestJitterStaysInBounds(t *testing.T) {
+	d := 100 * time.Millisecond
+	for i := 0; i < 100; i++ {
+		got := jitter(d)
+		if got < d/2 || got >= d {
+			t.Fatalf("jitter(%v) = %v, want within [%v, %v)", d, got, d/2, d)
+		}
+	}
+	if jitter(0) != 0 {
+		t.Fatalf("jitter(0) = %v, want 0", jitter(0))
+	}
+}
+
+func TestIsRetryableClassification(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"context canceled", context.Canceled, false},
+		{"context deadline exceeded", context.DeadlineExceeded, false},
+		{"broken pipe", errors.New("write: broken pipe"), true},
+		{"slow down", errors.New("SlowDown: please reduce your request rate"), true},
+		{"internal error", errors.New("InternalError: we encountered an internal error"), true},
+		{"not found", errors.New("NoSuchKey: the specified key does not exist"), false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := IsRetryable(tc.err); got != tc.want {
+				t.Fatalf("IsRetryable(%v) = %v, want %v", tc.err, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+// Package s3 wraps the AWS SDK S3 client with the handful of operations
+// appcloud-backman-app needs: streaming uploads, listing and downloading
+// backup objects from a single bucket.
+package s3
+
+import (
+	"context"
+	"io"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	awss3 "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// defaultPartSize is the size of each multipart chunk buffered from the
+// upload reader before it is handed off to a worker. 32 MiB keeps memory use
+// bounded (PartSize * FlushConcurrency) regardless of the overall object
+// size, while staying comfortably below S3's 10,000-part ceiling for dumps
+// up to several hundred GB.
+const defaultPartSize = 32 * 1024 * 1024
+
+// defaultFlushConcurrency is how many parts are uploaded in parallel.
+const defaultFlushConcurrency = 4
+
+// defaultMaxRetries mirrors the AWS SDK's own default retry count, applied
+// when NewClient is given a non-positive maxRetries.
+const defaultMaxRetries = 3
+
+// Client is a thin wrapper around the AWS S3 SDK, scoped to a single bucket.
+type Client struct {
+	Bucket string
+
+	// PartSize is the size in bytes of each multipart chunk buffered from
+	// an UploadWithContext reader. Defaults to 32 MiB.
+	PartSize int64
+	// FlushConcurrency is how many parts UploadWithContext uploads in
+	// parallel. Defaults to 4.
+	FlushConcurrency int
+
+	svc      *awss3.S3
+	uploader *s3manager.Uploader
+}
+
+// NewClient builds a Client for the given bucket using the provided session.
+// maxRetries configures the AWS SDK's own request-level retryer (handshake
+// failures, 5xx, throttling), which retries each individual part PUT inside
+// UploadWithContext independently of any caller-level retry around
+// UploadWithContext as a whole. Non-positive values default to 3.
+func NewClient(sess *session.Session, bucket string, maxRetries int) *Client {
+	if maxRetries <= 0 {
+		maxRetries = defaultMaxRetries
+	}
+	sess = sess.Copy(aws.NewConfig().WithMaxRetries(maxRetries))
+	c := &Client{
+		Bucket:           bucket,
+		PartSize:         defaultPartSize,
+		FlushConcurrency: defaultFlushConcurrency,
+		svc:              awss3.New(sess),
+	}
+	c.uploader = s3manager.NewUploader(sess, func(u *s3manager.Uploader) {
+		u.PartSize = c.PartSize
+		u.Concurrency = c.FlushConcurrency
+	})
+	return c
+}
+
+// WithPartSize returns a copy of the client that buffers partSize chunks
+// and uploads up to flushConcurrency of them in parallel, leaving c
+// untouched. Zero values fall back to the package defaults.
+func (c *Client) WithPartSize(partSize int64, flushConcurrency int) *Client {
+	clone := *c
+	if partSize <= 0 {
+		partSize = defaultPartSize
+	}
+	if flushConcurrency <= 0 {
+		flushConcurrency = defaultFlushConcurrency
+	}
+	clone.PartSize = partSize
+	clone.FlushConcurrency = flushConcurrency
+	clone.uploader = &s3manager.Uploader{}
+	*clone.uploader = *c.uploader
+	clone.uploader.PartSize = partSize
+	clone.uploader.Concurrency = flushConcurrency
+	return &clone
+}
+
+// UploadWithContext streams reader to objectPath in the client's bucket,
+// buffering it into PartSize chunks and uploading up to FlushConcurrency of
+// them in parallel. Memory use is bounded to roughly PartSize *
+// FlushConcurrency regardless of the total size of reader. A negative size
+// indicates the size is unknown ahead of time.
+//
+// Each part PUT already gets its own retries from the AWS SDK's retryer
+// (see NewClient's maxRetries); UploadWithContext does not additionally
+// wrap itself in the retry package, since reader is a one-shot stream (often
+// a pipe fed by a live pg_dump) that can't be re-read from the start once
+// partially consumed. Retrying the whole dump-and-upload pipeline from
+// scratch is the caller's job — see postgres.Backup.
+func (c *Client) UploadWithContext(ctx context.Context, objectPath string, reader io.Reader, size int64) error {
+	_, err := c.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket: aws.String(c.Bucket),
+		Key:    aws.String(objectPath),
+		Body:   reader,
+	})
+	return err
+}
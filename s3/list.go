@@ -0,0 +1,57 @@
+package s3
+
+import (
+	"context"
+	"io"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awss3 "github.com/aws/aws-sdk-go/service/s3"
+)
+
+// Object describes a single backup object found under a prefix.
+type Object struct {
+	Key          string
+	Size         int64
+	LastModified int64 // unix seconds
+}
+
+// ListWithContext lists every object under prefix in the client's bucket,
+// newest first.
+func (c *Client) ListWithContext(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	err := c.svc.ListObjectsV2PagesWithContext(ctx, &awss3.ListObjectsV2Input{
+		Bucket: aws.String(c.Bucket),
+		Prefix: aws.String(prefix),
+	}, func(page *awss3.ListObjectsV2Output, lastPage bool) bool {
+		for _, obj := range page.Contents {
+			objects = append(objects, Object{
+				Key:          aws.StringValue(obj.Key),
+				Size:         aws.Int64Value(obj.Size),
+				LastModified: obj.LastModified.Unix(),
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].LastModified > objects[j].LastModified
+	})
+	return objects, nil
+}
+
+// DownloadWithContext fetches objectPath from the client's bucket and
+// returns its body. The caller is responsible for closing it.
+func (c *Client) DownloadWithContext(ctx context.Context, objectPath string) (io.ReadCloser, error) {
+	out, err := c.svc.GetObjectWithContext(ctx, &awss3.GetObjectInput{
+		Bucket: aws.String(c.Bucket),
+		Key:    aws.String(objectPath),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Body, nil
+}
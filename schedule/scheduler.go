@@ -0,0 +1,88 @@
+// Package schedule implements a bounded worker pool keyed by an arbitrary
+// string, following the bounded-concurrency pattern used by ghloc: a global
+// semaphore caps total concurrency, while per-key semaphores cap how many
+// units of work for the same key run at once.
+package schedule
+
+import (
+	"context"
+	"sync"
+)
+
+// Scheduler bounds concurrent work both overall and per key.
+type Scheduler struct {
+	global chan struct{}
+
+	mu     sync.Mutex
+	perKey map[keyLimit]chan struct{}
+}
+
+// keyLimit identifies a per-key semaphore by both the key and the limit it
+// was created with, so two callers sharing a key but wanting different
+// bounds (e.g. Backup's configurable ServiceConcurrency vs Restore's fixed
+// 1) each get a correctly-sized semaphore instead of silently sharing
+// whichever capacity the first caller happened to request.
+type keyLimit struct {
+	key   string
+	limit int
+}
+
+// New returns a Scheduler allowing at most maxConcurrent units of work
+// across all keys at once. maxConcurrent <= 0 is treated as 1.
+func New(maxConcurrent int) *Scheduler {
+	if maxConcurrent <= 0 {
+		maxConcurrent = 1
+	}
+	return &Scheduler{
+		global: make(chan struct{}, maxConcurrent),
+		perKey: make(map[keyLimit]chan struct{}),
+	}
+}
+
+// Acquire blocks until a global slot and a slot for key are both available,
+// or ctx is done. limit bounds how many units of work for key run
+// concurrently; limit <= 0 is treated as 1. Two Acquire calls for the same
+// key but different limits are bounded independently of each other (see
+// keySemaphore). The returned release func must be called to free the
+// slots once the work is done.
+func (s *Scheduler) Acquire(ctx context.Context, key string, limit int) (release func(), err error) {
+	keySem := s.keySemaphore(key, limit)
+
+	select {
+	case s.global <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	select {
+	case keySem <- struct{}{}:
+	case <-ctx.Done():
+		<-s.global
+		return nil, ctx.Err()
+	}
+
+	return func() {
+		<-keySem
+		<-s.global
+	}, nil
+}
+
+// keySemaphore returns the semaphore for (key, limit), creating it on first
+// use. It is keyed by limit as well as key so that callers requesting
+// different bounds for the same key never share, or silently inherit,
+// each other's capacity.
+func (s *Scheduler) keySemaphore(key string, limit int) chan struct{} {
+	if limit <= 0 {
+		limit = 1
+	}
+	kl := keyLimit{key: key, limit: limit}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sem, ok := s.perKey[kl]
+	if !ok {
+		sem = make(chan struct{}, limit)
+		s.perKey[kl] = sem
+	}
+	return sem
+}
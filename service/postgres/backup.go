@@ -2,28 +2,55 @@ package postgres
 
 import (
 	"bytes"
-	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/cloudfoundry-community/go-cfenv"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/stdlib"
+	"gitlab.swisscloud.io/appc-cf-core/appcloud-backman-app/compress"
+	"gitlab.swisscloud.io/appc-cf-core/appcloud-backman-app/crypt"
 	"gitlab.swisscloud.io/appc-cf-core/appcloud-backman-app/log"
+	"gitlab.swisscloud.io/appc-cf-core/appcloud-backman-app/retry"
 	"gitlab.swisscloud.io/appc-cf-core/appcloud-backman-app/s3"
 )
 
-var pgMutex = &sync.Mutex{}
+// Backup runs pg_dump (or pg_dumpall) against binding and uploads the
+// compressed (and, if configured, encrypted) result to S3, retrying the
+// whole dump-and-upload pipeline from scratch up to cfg.Retry.MaxAttempts
+// times if it fails with a retryable error.
+func Backup(ctx context.Context, s3Client *s3.Client, binding *cfenv.Service, filename string, cfg Config) error {
+	var attempt int
+	return retry.Do(ctx, cfg.Retry, nil, func() error {
+		attempt++
+		if attempt > 1 {
+			log.Warnf("retrying postgres backup for service [%s], attempt %d", binding.Name, attempt)
+		}
+		return backupOnce(ctx, s3Client, binding, filename, cfg)
+	})
+}
+
+func backupOnce(ctx context.Context, s3 *s3.Client, binding *cfenv.Service, filename string, cfg Config) error {
+	// bound how many backups of this service, and of the process overall,
+	// run at once; see the schedule package
+	release, err := scheduler.Acquire(ctx, serviceKey(binding), cfg.ServiceConcurrency)
+	if err != nil {
+		return err
+	}
+	defer release()
 
-func Backup(ctx context.Context, s3 *s3.Client, binding *cfenv.Service, filename string) error {
-	// lock global postgres mutex, only 1 backup of this service-type is allowed to run in parallel
-	// to avoid issues with setting PG* environments variables and memory consumption
-	pgMutex.Lock()
-	defer pgMutex.Unlock()
+	if cfg.UploadPartSize > 0 || cfg.UploadFlushConcurrency > 0 {
+		s3 = s3.WithPartSize(cfg.UploadPartSize, cfg.UploadFlushConcurrency)
+	}
 
 	host, _ := binding.CredentialString("host")
 	port, _ := binding.CredentialString("port")
@@ -31,11 +58,6 @@ func Backup(ctx context.Context, s3 *s3.Client, binding *cfenv.Service, filename
 	username, _ := binding.CredentialString("username")
 	password, _ := binding.CredentialString("password")
 
-	os.Setenv("PGUSER", username)
-	os.Setenv("PGPASSWORD", password)
-	os.Setenv("PGHOST", host)
-	os.Setenv("PGPORT", port)
-
 	// prepare postgres dump command
 	var command []string
 	if len(database) > 0 {
@@ -48,10 +70,22 @@ func Backup(ctx context.Context, s3 *s3.Client, binding *cfenv.Service, filename
 	command = append(command, "-c")
 	command = append(command, "--no-password")
 
+	// allows the dump to be aborted early by the alive checker below
+	dumpCtx, dumpCancel := context.WithCancel(ctx)
+	defer dumpCancel()
+
 	log.Debugf("executing postgres backup command: %v", strings.Join(command, " "))
-	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd := exec.CommandContext(dumpCtx, command[0], command[1:]...)
+	// pass credentials via the command's own environment rather than
+	// os.Setenv, which would race with other backups running concurrently
+	cmd.Env = append(os.Environ(),
+		"PGUSER="+username,
+		"PGPASSWORD="+password,
+		"PGHOST="+host,
+		"PGPORT="+port,
+	)
 
-	// capture stdout to pass to gzipping buffer
+	// capture stdout to pass to the compressing/encrypting pipe
 	outPipe, err := cmd.StdoutPipe()
 	if err != nil {
 		log.Errorf("could not get stdout pipe for postgres dump: %v", err)
@@ -63,46 +97,213 @@ func Backup(ctx context.Context, s3 *s3.Client, binding *cfenv.Service, filename
 	var errBuf bytes.Buffer
 	cmd.Stderr = &errBuf
 
+	dumpVersion := toolVersion(ctx, command[0])
+
+	startedAt := time.Now()
 	if err := cmd.Start(); err != nil {
 		log.Errorf("could not run postgres dump: %v", err)
 		return err
 	}
 
+	var (
+		aliveErr    error
+		aliveWait   sync.WaitGroup
+		aliveCancel context.CancelFunc
+	)
+	if cfg.aliveCheckEnabled() {
+		var aliveCtx context.Context
+		aliveCtx, aliveCancel = context.WithCancel(context.Background())
+
+		aliveWait.Add(1)
+		go func() {
+			defer aliveWait.Done()
+			aliveErr = watchAlive(aliveCtx, host, port, database, username, password, cfg, dumpCancel)
+		}()
+	}
+
+	compressor, err := compress.ByName(cfg.Compression)
+	if err != nil {
+		return err
+	}
+
+	objectPath := fmt.Sprintf("%s/%s/%s", binding.Label, binding.Name, objectName(filename, compressor, cfg.EncryptionKey))
+
 	var uploadWait sync.WaitGroup
 	uploadCtx, uploadCancel := context.WithCancel(context.Background()) // allows upload to be cancelable, in case backup times out
 	defer uploadCancel()
+
+	hasher := sha256.New()
+	var counter byteCounter
 	go func() {
 		defer uploadWait.Done()
 		uploadWait.Add(1)
 
-		// gzipping stdout
 		pr, pw := io.Pipe()
-		gw := gzip.NewWriter(pw)
-		gw.Name = filename
-		gw.ModTime = time.Now()
 		go func() {
-			defer pw.Close()
-			defer gw.Close()
-			_, _ = io.Copy(gw, outPipe)
+			pw.CloseWithError(encode(pw, outPipe, compressor, cfg.EncryptionKey))
 		}()
 
-		objectPath := fmt.Sprintf("%s/%s/%s", binding.Label, binding.Name, filename)
-		err = s3.UploadWithContext(uploadCtx, objectPath, pr, -1)
+		// tee the compressed/encrypted stream into the manifest's checksum
+		// as it is uploaded, rather than re-reading the object afterwards
+		tee := io.TeeReader(pr, io.MultiWriter(hasher, &counter))
+		err = s3.UploadWithContext(uploadCtx, objectPath, tee, -1)
 		if err != nil {
 			log.Errorf("could not upload service backup [%s] to S3: %v", binding.Name, err)
 		}
 	}()
 
-	if err := cmd.Wait(); err != nil {
+	dumpErr := cmd.Wait()
+
+	// stop the alive checker and wait for it to finish before reading
+	// aliveErr below, so the watcher goroutine's assignment can't race the
+	// read (aliveCancel is nil, and aliveWait.Wait() returns immediately,
+	// if the check was never started)
+	if aliveCancel != nil {
+		aliveCancel()
+	}
+	aliveWait.Wait()
+
+	if dumpErr != nil {
 		// check for timeout error
 		if ctx.Err() == context.DeadlineExceeded {
 			return fmt.Errorf("postgres dump: timeout: %v", ctx.Err())
 		}
 
+		// the alive checker cancels dumpCtx, not ctx, so surface its reason
+		// instead of the generic "signal: killed" exec error
+		if aliveErr != nil {
+			return fmt.Errorf("postgres dump: aborted: %v", aliveErr)
+		}
+
 		log.Errorln(strings.TrimRight(errBuf.String(), "\r\n"))
-		return fmt.Errorf("postgres dump: %v", err)
+		return fmt.Errorf("postgres dump: %v", dumpErr)
 	}
 
 	uploadWait.Wait() // wait for upload to have finished
-	return err
+	if err != nil {
+		return err
+	}
+
+	manifest := Manifest{
+		ServiceLabel: binding.Label,
+		ServiceName:  binding.Name,
+		Database:     database,
+		Command:      strings.Join(command, " "),
+		ToolVersion:  dumpVersion,
+		StartedAt:    startedAt,
+		FinishedAt:   time.Now(),
+		Bytes:        counter.n,
+		SHA256:       hex.EncodeToString(hasher.Sum(nil)),
+		Compression:  compressor.Name(),
+		Encrypted:    len(cfg.EncryptionKey) > 0,
+		ExitStatus:   cmd.ProcessState.ExitCode(),
+		StderrTail:   tail(errBuf.String(), 20),
+	}
+	if err := writeManifest(ctx, s3, objectPath, manifest); err != nil {
+		log.Errorf("could not write manifest for backup [%s]: %v", binding.Name, err)
+		return err
+	}
+	return nil
+}
+
+// encode compresses src with compressor and, if key is set, seals the
+// compressed stream with AES-256-GCM, writing the result to dst, so the
+// bytes reaching dst are encrypt(compress(src)) — the order Restore and
+// objectName already assume (decrypt the raw object, then decompress).
+// Layers are closed innermost-first so their trailing frames/footers reach
+// dst before it is closed by the caller.
+func encode(dst io.Writer, src io.Reader, compressor compress.Compressor, key []byte) error {
+	if len(key) > 0 {
+		ew, err := crypt.NewWriter(dst, key)
+		if err != nil {
+			return fmt.Errorf("could not set up encryption: %v", err)
+		}
+		w, err := compressor.NewWriter(ew)
+		if err != nil {
+			return fmt.Errorf("could not set up %s compressor: %v", compressor.Name(), err)
+		}
+		if _, err := io.Copy(w, src); err != nil {
+			return err
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+		return ew.Close()
+	}
+
+	w, err := compressor.NewWriter(dst)
+	if err != nil {
+		return fmt.Errorf("could not set up %s compressor: %v", compressor.Name(), err)
+	}
+	if _, err := io.Copy(w, src); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// objectName derives the S3 object key for filename under compressor and
+// the chosen encryption, e.g. "dump.sql" becomes "dump.sql.zst.enc".
+func objectName(filename string, compressor compress.Compressor, key []byte) string {
+	name := strings.TrimSuffix(filename, ".gz")
+	name += compressor.Extension()
+	if len(key) > 0 {
+		name += ".enc"
+	}
+	return name
+}
+
+// watchAlive periodically pings the source database while a dump is in
+// flight and cancels dump via dumpCancel once threshold consecutive checks
+// have failed. It returns the failure that triggered the abort, or nil if
+// ctx was cancelled first (the dump finished normally).
+func watchAlive(ctx context.Context, host, port, database, username, password string, cfg Config, dumpCancel context.CancelFunc) error {
+	connDatabase := database
+	if len(connDatabase) == 0 {
+		connDatabase = "postgres"
+	}
+
+	// build the connection field-by-field rather than interpolating a
+	// "key=value" conninfo string, so a password containing a space, quote
+	// or backslash can't break or silently truncate it
+	connCfg, err := pgx.ParseConfig("")
+	if err != nil {
+		log.Errorf("alive check: could not build connection config: %v", err)
+		return nil
+	}
+	connCfg.Host = host
+	connCfg.Database = connDatabase
+	connCfg.User = username
+	connCfg.Password = password
+	if p, err := strconv.ParseUint(port, 10, 16); err == nil {
+		connCfg.Port = uint16(p)
+	}
+
+	db := stdlib.OpenDB(*connCfg)
+	defer db.Close()
+
+	ticker := time.NewTicker(cfg.AliveCheckInterval)
+	defer ticker.Stop()
+
+	var failures int
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			pingCtx, cancel := context.WithTimeout(ctx, cfg.aliveCheckTimeout())
+			_, err := db.ExecContext(pingCtx, "SELECT 1")
+			cancel()
+			if err != nil {
+				failures++
+				log.Warnf("alive check: source postgres unreachable (%d/%d): %v", failures, cfg.failureThreshold(), err)
+				if failures >= cfg.failureThreshold() {
+					lastErr := fmt.Errorf("source postgres failed %d consecutive liveness checks: %v", failures, err)
+					dumpCancel()
+					return lastErr
+				}
+				continue
+			}
+			failures = 0
+		}
+	}
 }
@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"time"
+
+	"gitlab.swisscloud.io/appc-cf-core/appcloud-backman-app/retry"
+)
+
+// Config controls the optional safety nets around a single Backup run. The
+// zero value disables the liveness check and retries the pipeline the
+// retry package's own defaults.
+type Config struct {
+	// AliveCheckInterval is how often the liveness check pings the source
+	// database while pg_dump is running. Set to 0 to disable the check
+	// entirely, mirroring WAL-G's WALG_ALIVE_CHECK_INTERVAL.
+	AliveCheckInterval time.Duration
+	// AliveCheckFailureThreshold is how many consecutive liveness failures
+	// are tolerated before the dump is aborted. Defaults to 1 if unset while
+	// AliveCheckInterval is set.
+	AliveCheckFailureThreshold int
+	// AliveCheckTimeout bounds each individual liveness query.
+	AliveCheckTimeout time.Duration
+	// Retry controls how Backup retries the whole pg_dump-and-upload
+	// pipeline when it fails with a retryable error.
+	Retry retry.Config
+	// UploadPartSize and UploadFlushConcurrency override the S3 client's
+	// multipart upload chunk size and parallelism for this service. Zero
+	// keeps the client's own defaults.
+	UploadPartSize         int64
+	UploadFlushConcurrency int
+	// Compression selects the algorithm backups are written with: "gzip"
+	// (the default), "zstd" or "lz4". See the compress package.
+	Compression string
+	// EncryptionKey, if set, must be a 32-byte AES-256 key; backups are then
+	// sealed with AES-256-GCM (see the crypt package) before upload. Derive
+	// it from a passphrase with crypt.DeriveKey, or fetch it from a KMS.
+	EncryptionKey []byte
+	// ServiceConcurrency bounds how many backups of this same service
+	// instance (binding.Label/binding.Name) run concurrently. Defaults to 1:
+	// dumping the same database twice at once is rarely useful and doubles
+	// load on it for no benefit. The process-wide limit set by
+	// SetMaxConcurrentBackups always applies on top of this.
+	ServiceConcurrency int
+}
+
+func (c Config) aliveCheckEnabled() bool {
+	return c.AliveCheckInterval > 0
+}
+
+func (c Config) failureThreshold() int {
+	if c.AliveCheckFailureThreshold > 0 {
+		return c.AliveCheckFailureThreshold
+	}
+	return 1
+}
+
+func (c Config) aliveCheckTimeout() time.Duration {
+	if c.AliveCheckTimeout > 0 {
+		return c.AliveCheckTimeout
+	}
+	return 5 * time.Second
+}
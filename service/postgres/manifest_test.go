@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestVerifyChecksumAccepts(t *testing.T) {
+	data := []byte("a backup object's compressed bytes")
+	sum := sha256.Sum256(data)
+	m := Manifest{Bytes: int64(len(data)), SHA256: hex.EncodeToString(sum[:])}
+
+	if err := verifyChecksum(bytes.NewReader(data), m, "svc/0/dump.sql.gz"); err != nil {
+		t.Fatalf("verifyChecksum: %v", err)
+	}
+}
+
+func TestVerifyChecksumRejectsCorruptedContent(t *testing.T) {
+	data := []byte("a backup object's compressed bytes")
+	sum := sha256.Sum256(data)
+	m := Manifest{Bytes: int64(len(data)), SHA256: hex.EncodeToString(sum[:])}
+
+	corrupted := append([]byte{}, data...)
+	corrupted[0] ^= 0xff
+
+	err := verifyChecksum(bytes.NewReader(corrupted), m, "svc/0/dump.sql.gz")
+	if err == nil {
+		t.Fatal("verifyChecksum on corrupted content: want error, got nil")
+	}
+	if !strings.Contains(err.Error(), "sha256") {
+		t.Fatalf("error %q does not mention the checksum mismatch", err)
+	}
+}
+
+func TestVerifyChecksumRejectsSizeMismatch(t *testing.T) {
+	data := []byte("a backup object's compressed bytes")
+	sum := sha256.Sum256(data)
+	m := Manifest{Bytes: int64(len(data)) + 1, SHA256: hex.EncodeToString(sum[:])}
+
+	err := verifyChecksum(bytes.NewReader(data), m, "svc/0/dump.sql.gz")
+	if err == nil {
+		t.Fatal("verifyChecksum on size mismatch: want error, got nil")
+	}
+	if !strings.Contains(err.Error(), "bytes") {
+		t.Fatalf("error %q does not mention the size mismatch", err)
+	}
+}
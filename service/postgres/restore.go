@@ -0,0 +1,165 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry-community/go-cfenv"
+	"gitlab.swisscloud.io/appc-cf-core/appcloud-backman-app/compress"
+	"gitlab.swisscloud.io/appc-cf-core/appcloud-backman-app/crypt"
+	"gitlab.swisscloud.io/appc-cf-core/appcloud-backman-app/log"
+	"gitlab.swisscloud.io/appc-cf-core/appcloud-backman-app/s3"
+)
+
+// StoredBackup is the subset of s3.Object interesting to callers picking a
+// backup to restore.
+type StoredBackup struct {
+	ObjectPath   string
+	LastModified time.Time
+}
+
+// ListBackups enumerates the backups stored for binding, newest first.
+func ListBackups(ctx context.Context, client *s3.Client, binding *cfenv.Service) ([]StoredBackup, error) {
+	prefix := fmt.Sprintf("%s/%s/", binding.Label, binding.Name)
+	objects, err := client.ListWithContext(ctx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("could not list backups for service [%s]: %v", binding.Name, err)
+	}
+
+	backups := make([]StoredBackup, 0, len(objects))
+	for _, obj := range objects {
+		// manifests are written under the same prefix as the backup they
+		// describe, but aren't themselves restorable
+		if strings.HasSuffix(obj.Key, manifestSuffix) {
+			continue
+		}
+		backups = append(backups, StoredBackup{
+			ObjectPath:   obj.Key,
+			LastModified: time.Unix(obj.LastModified, 0),
+		})
+	}
+	// client already returns newest first, but keep the sort explicit so this
+	// still holds if that guarantee ever changes.
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].LastModified.After(backups[j].LastModified)
+	})
+	return backups, nil
+}
+
+// Restore downloads objectPath from S3, reverses its AES-256-GCM encryption
+// with encryptionKey (if the object name ends in ".enc") and its compression
+// (detected from the object name's extension), and pipes the resulting dump
+// into psql or pg_restore as restoreCommand picks. encryptionKey may be nil
+// for backups that were not encrypted.
+func Restore(ctx context.Context, client *s3.Client, binding *cfenv.Service, objectPath string, encryptionKey []byte) error {
+	// a restore is inherently exclusive for its service, so only the
+	// process-wide limit applies on top of that; see the schedule package
+	release, err := scheduler.Acquire(ctx, serviceKey(binding), 1)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	host, _ := binding.CredentialString("host")
+	port, _ := binding.CredentialString("port")
+	database, _ := binding.CredentialString("database")
+	username, _ := binding.CredentialString("username")
+	password, _ := binding.CredentialString("password")
+
+	body, err := client.DownloadWithContext(ctx, objectPath)
+	if err != nil {
+		log.Errorf("could not download backup [%s] from S3: %v", objectPath, err)
+		return err
+	}
+	defer body.Close()
+
+	var reader = io.ReadCloser(body)
+	if strings.HasSuffix(objectPath, ".enc") {
+		reader, err = crypt.NewReader(reader, encryptionKey)
+		if err != nil {
+			log.Errorf("could not set up decryption for backup [%s]: %v", objectPath, err)
+			return err
+		}
+		defer reader.Close()
+	}
+
+	compressor, err := compress.ByExtension(strings.TrimSuffix(objectPath, ".enc"))
+	if err != nil {
+		log.Errorf("could not detect compression for backup [%s]: %v", objectPath, err)
+		return err
+	}
+	gr, err := compressor.NewReader(reader)
+	if err != nil {
+		log.Errorf("could not decompress backup [%s]: %v", objectPath, err)
+		return err
+	}
+	defer gr.Close()
+
+	command, err := restoreCommand(objectPath, database)
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("executing postgres restore command: %v", strings.Join(command, " "))
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Stdin = gr
+	cmd.Env = append(os.Environ(),
+		"PGUSER="+username,
+		"PGPASSWORD="+password,
+		"PGHOST="+host,
+		"PGPORT="+port,
+	)
+
+	var errBuf bytes.Buffer
+	cmd.Stderr = &errBuf
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("postgres restore: timeout: %v", ctx.Err())
+		}
+		log.Errorln(strings.TrimRight(errBuf.String(), "\r\n"))
+		return fmt.Errorf("postgres restore: %v", err)
+	}
+	return nil
+}
+
+// restoreCommand picks psql or pg_restore from the backup's object name: a
+// custom-format archive (see isCustomFormatDump) is restored with
+// pg_restore, anything else is treated as a plain SQL dump for psql.
+// Directory and tar archives are not detected.
+func restoreCommand(objectPath, database string) ([]string, error) {
+	if isCustomFormatDump(objectPath) {
+		command := []string{"pg_restore", "-c", "--no-password"}
+		if len(database) > 0 {
+			command = append(command, "-d", database)
+		}
+		return command, nil
+	}
+
+	// a pg_dumpall script switches databases itself via \connect, so psql
+	// only needs somewhere to connect to, not a target to dump into;
+	// default to "postgres" like the liveness checker does
+	connectDatabase := database
+	if len(connectDatabase) == 0 {
+		connectDatabase = "postgres"
+	}
+	return []string{"psql", "-v", "ON_ERROR_STOP=1", "--no-password", connectDatabase}, nil
+}
+
+// isCustomFormatDump reports whether the object was produced by pg_dump's
+// custom archive format (-Fc), recognisable by its .dump suffix ahead of
+// whatever compression/encryption extensions this package appends.
+func isCustomFormatDump(objectPath string) bool {
+	objectPath = strings.TrimSuffix(objectPath, ".enc")
+	if compressor, err := compress.ByExtension(objectPath); err == nil {
+		objectPath = strings.TrimSuffix(objectPath, compressor.Extension())
+	}
+	return strings.HasSuffix(objectPath, ".dump")
+}
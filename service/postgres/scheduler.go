@@ -0,0 +1,26 @@
+package postgres
+
+import (
+	"github.com/cloudfoundry-community/go-cfenv"
+	"gitlab.swisscloud.io/appc-cf-core/appcloud-backman-app/schedule"
+)
+
+// defaultMaxConcurrentBackups is the process-wide cap on concurrent postgres
+// backups (and restores) across all services until SetMaxConcurrentBackups
+// overrides it.
+const defaultMaxConcurrentBackups = 4
+
+var scheduler = schedule.New(defaultMaxConcurrentBackups)
+
+// SetMaxConcurrentBackups configures the process-wide cap on how many
+// postgres backups run at once, across all services. Call it once during
+// startup, before the first Backup or Restore.
+func SetMaxConcurrentBackups(n int) {
+	scheduler = schedule.New(n)
+}
+
+// serviceKey identifies a service instance for per-service concurrency
+// limits, matching the S3 prefix its backups are stored under.
+func serviceKey(binding *cfenv.Service) string {
+	return binding.Label + "/" + binding.Name
+}